@@ -1,9 +1,15 @@
 package gahttp
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"time"
+
+	"golang.org/x/net/proxy"
 )
 
 // NewDefaultClient returns the default HTTP client
@@ -22,6 +28,10 @@ const (
 
 	// Skip verification of TLS certificates
 	SkipVerify
+
+	// Honor the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables,
+	// mirroring the behavior of http.ProxyFromEnvironment
+	ProxyFromEnvironment
 )
 
 // NewClient returns a new client with the specified options
@@ -33,6 +43,10 @@ func NewClient(opts ClientOptions) *http.Client {
 		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	}
 
+	if opts&ProxyFromEnvironment > 0 {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+
 	client := &http.Client{
 		Transport: transport,
 		Timeout:   time.Second * 30,
@@ -46,3 +60,37 @@ func NewClient(opts ClientOptions) *http.Client {
 
 	return client
 }
+
+// NewClientWithProxy returns a new client with the specified options that
+// tunnels all requests through the proxy at proxyURL. Both http(s):// and
+// socks5:// URLs are supported.
+func NewClientWithProxy(opts ClientOptions, proxyURL string) (*http.Client, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy URL: %s", err)
+	}
+
+	client := NewClient(opts)
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unexpected transport type %T", client.Transport)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		transport.Proxy = http.ProxyURL(u)
+	case "socks5":
+		dialer, err := proxy.FromURL(u, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SOCKS5 dialer: %s", err)
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.Dial(network, addr)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	return client, nil
+}
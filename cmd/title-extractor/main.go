@@ -5,7 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"net/http"
-	"net/url"
+	"os"
 	"strings"
 
 	"github.com/fcynic3/gahttp"
@@ -48,18 +48,13 @@ func main() {
 	flag.StringVar(&proxyURL, "proxy", "", "Proxy URL")
 	flag.Parse()
 
-	p := gahttp.NewPipeline()
-	p.SetConcurrency(concurrency)
+	p := gahttp.New(concurrency)
 
 	if proxyURL != "" {
-		proxyURL, err := url.Parse(proxyURL)
-		if err != nil {
-			fmt.Println("Failed to parse proxy URL:", err)
+		if err := p.SetProxy(proxyURL); err != nil {
+			fmt.Println("Failed to set proxy:", err)
 			return
 		}
-		p.HTTPClient.Transport = &http.Transport{
-			Proxy: http.ProxyURL(proxyURL),
-		}
 	}
 
 	extractFn := gahttp.Wrap(extractTitle, gahttp.CloseBody)
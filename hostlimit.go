@@ -0,0 +1,110 @@
+package gahttp
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// hostLimitTransport gates outgoing requests with a per-host semaphore, so a
+// pipeline with high overall concurrency doesn't hammer any single origin.
+type hostLimitTransport struct {
+	next http.RoundTripper
+
+	mu     sync.Mutex
+	def    int
+	limits map[string]int
+	sems   map[string]chan struct{}
+}
+
+func newHostLimitTransport(next http.RoundTripper) *hostLimitTransport {
+	return &hostLimitTransport{
+		next:   next,
+		limits: make(map[string]int),
+		sems:   make(map[string]chan struct{}),
+	}
+}
+
+// semaphore returns host's semaphore, creating it on first use, and whether
+// host is explicitly blocked. An explicit per-host limit of 0 (set via
+// Pipeline.SetHostConcurrency) is distinct from no limit being set at all:
+// the former rejects every request to host outright, the latter falls back
+// to the default from Pipeline.SetPerHostConcurrency (or no gating if that
+// hasn't been set either). A blocked host never hands out a semaphore, so no
+// goroutine is ever left waiting on one.
+func (t *hostLimitTransport) semaphore(host string) (sem chan struct{}, blocked bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n, explicit := t.limits[host]
+	if !explicit {
+		n = t.def
+	}
+	if n <= 0 {
+		return nil, explicit
+	}
+
+	if sem, ok := t.sems[host]; ok {
+		return sem, false
+	}
+
+	sem = make(chan struct{}, n)
+	t.sems[host] = sem
+
+	return sem, false
+}
+
+func (t *hostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sem, blocked := t.semaphore(req.URL.Host)
+	if blocked {
+		return nil, fmt.Errorf("gahttp: concurrency for host %q is set to 0, request rejected", req.URL.Host)
+	}
+	if sem == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	sem <- struct{}{}
+	defer func() { <-sem }()
+
+	return t.next.RoundTrip(req)
+}
+
+// Unwrap returns t's underlying RoundTripper, letting callers (e.g.
+// Pipeline.SetProxy) find an installed *http.Transport through it.
+func (t *hostLimitTransport) Unwrap() http.RoundTripper { return t.next }
+
+// hostLimitTransport returns p's *hostLimitTransport, installing one in
+// front of its current transport if one isn't already there.
+func (p *Pipeline) hostLimitTransport() *hostLimitTransport {
+	if t, ok := p.HTTPClient.Transport.(*hostLimitTransport); ok {
+		return t
+	}
+
+	t := newHostLimitTransport(transportOrDefault(p.HTTPClient.Transport))
+	p.HTTPClient.Transport = t
+
+	return t
+}
+
+// SetHostConcurrency limits the number of concurrent in-flight requests p
+// makes to host to n, overriding the default set by SetPerHostConcurrency
+// for that host.
+func (p *Pipeline) SetHostConcurrency(host string, n int) {
+	t := p.hostLimitTransport()
+
+	t.mu.Lock()
+	t.limits[host] = n
+	delete(t.sems, host)
+	t.mu.Unlock()
+}
+
+// SetPerHostConcurrency limits the number of concurrent in-flight requests p
+// makes to any single host to n.
+func (p *Pipeline) SetPerHostConcurrency(n int) {
+	t := p.hostLimitTransport()
+
+	t.mu.Lock()
+	t.def = n
+	t.sems = make(map[string]chan struct{})
+	t.mu.Unlock()
+}
@@ -0,0 +1,45 @@
+package gahttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostLimitExplicitZeroRejectsRequests(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	p := New(1)
+	p.SetPerHostConcurrency(5)
+	p.SetHostConcurrency(ts.Listener.Addr().String(), 0)
+
+	var gotErr bool
+	p.Get(ts.URL, func(req *http.Request, resp *http.Response, err error) {
+		gotErr = err != nil
+	})
+	p.Done()
+	p.Wait()
+
+	if !gotErr {
+		t.Errorf("want an explicit zero host limit to reject the request with an error, not block forever")
+	}
+}
+
+func TestHostLimitDefaultAppliesToOtherHosts(t *testing.T) {
+	p := New(1)
+	p.SetPerHostConcurrency(5)
+	p.SetHostConcurrency("blocked.example", 0)
+
+	t1 := p.hostLimitTransport()
+
+	sem, blocked := t1.semaphore("other.example")
+	if blocked {
+		t.Fatalf("want hosts without an explicit limit to use the default, not be blocked")
+	}
+	if cap(sem) != 5 {
+		t.Errorf("want the default per-host concurrency to apply; have cap=%d", cap(sem))
+	}
+}
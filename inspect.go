@@ -0,0 +1,106 @@
+package gahttp
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// inspectTransport wraps a RoundTripper, running a pipeline's OnRequest and
+// OnResponse hooks around every request it makes.
+type inspectTransport struct {
+	next http.RoundTripper
+
+	mu         sync.Mutex
+	onRequest  []func(*http.Request) *http.Request
+	onResponse []func(*http.Response) *http.Response
+}
+
+func (t *inspectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	onRequest := t.onRequest
+	onResponse := t.onResponse
+	t.mu.Unlock()
+
+	for _, fn := range onRequest {
+		req = fn(req)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	for _, fn := range onResponse {
+		resp = fn(resp)
+	}
+
+	return resp, nil
+}
+
+// Unwrap returns t's underlying RoundTripper, letting callers (e.g.
+// Pipeline.SetProxy) find an installed *http.Transport through it.
+func (t *inspectTransport) Unwrap() http.RoundTripper { return t.next }
+
+// inspectTransport returns p's *inspectTransport, installing one in front of
+// its current transport if one isn't already there.
+func (p *Pipeline) inspectTransport() *inspectTransport {
+	if t, ok := p.HTTPClient.Transport.(*inspectTransport); ok {
+		return t
+	}
+
+	t := &inspectTransport{next: transportOrDefault(p.HTTPClient.Transport)}
+	p.HTTPClient.Transport = t
+
+	return t
+}
+
+// OnRequest registers fn to run, in registration order, on every request p
+// dispatches before it hits the network. fn's return value (e.g. a request
+// with injected headers or a rewritten URL) is used in place of the
+// original.
+func (p *Pipeline) OnRequest(fn func(*http.Request) *http.Request) {
+	t := p.inspectTransport()
+
+	t.mu.Lock()
+	t.onRequest = append(t.onRequest, fn)
+	t.mu.Unlock()
+}
+
+// OnResponse registers fn to run, in registration order, on every response p
+// receives before it reaches the caller's ProcFn. fn's return value (e.g. a
+// response with a transformed body) is used in place of the original.
+func (p *Pipeline) OnResponse(fn func(*http.Response) *http.Response) {
+	t := p.inspectTransport()
+
+	t.mu.Lock()
+	t.onResponse = append(t.onResponse, fn)
+	t.mu.Unlock()
+}
+
+// teeReadCloser tees reads from a ReadCloser through w, forwarding Close to
+// the original ReadCloser rather than discarding it.
+type teeReadCloser struct {
+	io.Reader
+	c io.Closer
+}
+
+func (t teeReadCloser) Close() error { return t.c.Close() }
+
+// TeeBody returns a ProcFn decorator that tees resp.Body through w as fn
+// reads it, so the response body can be archived to disk or stdout without
+// fetching it a second time. Closing the returned body (e.g. via CloseBody)
+// still closes the underlying connection.
+func TeeBody(w io.Writer) func(ProcFn) ProcFn {
+	return func(fn ProcFn) ProcFn {
+		return func(req *http.Request, resp *http.Response, err error) {
+			if resp != nil && resp.Body != nil {
+				resp.Body = teeReadCloser{
+					Reader: io.TeeReader(resp.Body, w),
+					c:      resp.Body,
+				}
+			}
+			fn(req, resp, err)
+		}
+	}
+}
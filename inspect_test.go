@@ -0,0 +1,74 @@
+package gahttp
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOnRequestOnResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Injected") != "yes" {
+			t.Errorf("want injected header to reach the server")
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	p := New(1)
+
+	p.OnRequest(func(req *http.Request) *http.Request {
+		req.Header.Set("X-Injected", "yes")
+		return req
+	})
+
+	var sawResponse bool
+	p.OnResponse(func(resp *http.Response) *http.Response {
+		sawResponse = true
+		return resp
+	})
+
+	p.Get(ts.URL, func(req *http.Request, resp *http.Response, err error) {
+		if err != nil {
+			t.Fatalf("request failed: %s", err)
+		}
+	})
+	p.Done()
+	p.Wait()
+
+	if !sawResponse {
+		t.Errorf("want OnResponse hook to run")
+	}
+}
+
+func TestTeeBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tee me"))
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %s", err)
+	}
+
+	var buf strings.Builder
+	fn := TeeBody(&buf)(func(req *http.Request, resp *http.Response, err error) {
+		if err != nil {
+			t.Fatalf("request failed: %s", err)
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+	})
+
+	p := New(1)
+	p.Do(req, fn)
+	p.Done()
+	p.Wait()
+
+	if buf.String() != "tee me" {
+		t.Errorf("want teed body to equal the response body; have %q", buf.String())
+	}
+}
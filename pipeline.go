@@ -0,0 +1,103 @@
+package gahttp
+
+import (
+	"net/http"
+	"sync"
+)
+
+// ProcFn is the signature of the function a Pipeline calls with the result
+// of a request it has dispatched.
+type ProcFn func(req *http.Request, resp *http.Response, err error)
+
+// job pairs a request with the ProcFn to call with its result.
+type job struct {
+	req *http.Request
+	fn  ProcFn
+}
+
+// Pipeline dispatches HTTP requests across a pool of worker goroutines,
+// limiting how many can be in flight at once.
+type Pipeline struct {
+	HTTPClient *http.Client
+
+	jobs chan job
+	wg   sync.WaitGroup
+}
+
+// New returns a new Pipeline with concurrency worker goroutines.
+func New(concurrency int) *Pipeline {
+	p := &Pipeline{
+		HTTPClient: NewDefaultClient(),
+		jobs:       make(chan job),
+	}
+
+	p.SetConcurrency(concurrency)
+
+	return p
+}
+
+// SetConcurrency starts n additional worker goroutines to process queued
+// requests. It's intended to be called once, before any requests are
+// dispatched.
+func (p *Pipeline) SetConcurrency(n int) {
+	for i := 0; i < n; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+}
+
+func (p *Pipeline) worker() {
+	defer p.wg.Done()
+
+	for j := range p.jobs {
+		resp, err := p.HTTPClient.Do(j.req)
+		j.fn(j.req, resp, err)
+	}
+}
+
+// Do queues req to be dispatched by a worker, which will call fn with the
+// result.
+func (p *Pipeline) Do(req *http.Request, fn ProcFn) {
+	p.jobs <- job{req: req, fn: fn}
+}
+
+// Get builds a GET request for url and queues it via Do.
+func (p *Pipeline) Get(url string, fn ProcFn) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	p.Do(req, fn)
+
+	return nil
+}
+
+// Done signals that no more requests will be queued, letting worker
+// goroutines exit once the queue drains.
+func (p *Pipeline) Done() {
+	close(p.jobs)
+}
+
+// Wait blocks until every queued request has been processed.
+func (p *Pipeline) Wait() {
+	p.wg.Wait()
+}
+
+// Wrap decorates fn with each of mw, in order, so mw[0] runs outermost.
+func Wrap(fn ProcFn, mw ...func(ProcFn) ProcFn) ProcFn {
+	for i := len(mw) - 1; i >= 0; i-- {
+		fn = mw[i](fn)
+	}
+	return fn
+}
+
+// CloseBody is a middleware that closes resp.Body after fn returns.
+func CloseBody(fn ProcFn) ProcFn {
+	return func(req *http.Request, resp *http.Response, err error) {
+		fn(req, resp, err)
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+	}
+}
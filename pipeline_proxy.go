@@ -0,0 +1,72 @@
+package gahttp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/proxy"
+)
+
+// unwrapper is implemented by the RoundTrippers this package installs (for
+// recording/replay, retries, per-host concurrency and request/response
+// inspection), so a wrapped chain can be walked down to the underlying
+// *http.Transport.
+type unwrapper interface {
+	Unwrap() http.RoundTripper
+}
+
+// baseTransport walks a chain of RoundTrippers wrapped by this package to
+// find the underlying *http.Transport, so SetProxy can configure it without
+// discarding the rest of the chain.
+func baseTransport(rt http.RoundTripper) *http.Transport {
+	for {
+		if t, ok := rt.(*http.Transport); ok {
+			return t
+		}
+
+		u, ok := rt.(unwrapper)
+		if !ok {
+			return nil
+		}
+		rt = u.Unwrap()
+	}
+}
+
+// SetProxy configures p's HTTP client to tunnel all requests through the
+// proxy at proxyURL. Both http(s):// and socks5:// URLs are supported. It
+// finds and reconfigures the underlying *http.Transport in place, so any
+// wrapping already installed by SetRetryPolicy, SetHostConcurrency,
+// OnRequest/OnResponse or the recording/replay constructors - along with
+// CheckRedirect and other client settings - is preserved.
+func (p *Pipeline) SetProxy(proxyURL string) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse proxy URL: %s", err)
+	}
+
+	transport := baseTransport(p.HTTPClient.Transport)
+	if transport == nil {
+		transport = &http.Transport{}
+		p.HTTPClient.Transport = transport
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		transport.Proxy = http.ProxyURL(u)
+	case "socks5":
+		dialer, err := proxy.FromURL(u, proxy.Direct)
+		if err != nil {
+			return fmt.Errorf("failed to create SOCKS5 dialer: %s", err)
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.Dial(network, addr)
+		}
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	return nil
+}
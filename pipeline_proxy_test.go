@@ -0,0 +1,35 @@
+package gahttp
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSetProxyPreservesWrappedTransportAndRedirectPolicy(t *testing.T) {
+	p := New(1)
+	p.HTTPClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	p.SetPerHostConcurrency(5)
+
+	if err := p.SetProxy("http://127.0.0.1:8080"); err != nil {
+		t.Fatalf("SetProxy: %s", err)
+	}
+
+	if _, ok := p.HTTPClient.Transport.(*hostLimitTransport); !ok {
+		t.Fatalf("want the per-host concurrency transport to survive SetProxy; have %T", p.HTTPClient.Transport)
+	}
+
+	if p.HTTPClient.CheckRedirect == nil {
+		t.Errorf("want CheckRedirect to survive SetProxy")
+	}
+
+	transport := baseTransport(p.HTTPClient.Transport)
+	if transport == nil {
+		t.Fatalf("want to find the underlying *http.Transport through the wrapper chain")
+	}
+	if transport.Proxy == nil {
+		t.Errorf("want the proxy to be configured on the underlying transport")
+	}
+}
@@ -0,0 +1,337 @@
+package gahttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScrubberFunc strips sensitive data (e.g. Authorization or Cookie headers)
+// from a request before it's written to a recording.
+type ScrubberFunc func(req *http.Request)
+
+// UnmatchedMode controls what a replaying pipeline does when it can't find a
+// recorded exchange for a request.
+type UnmatchedMode int
+
+const (
+	// ErrorOnUnmatched fails the request with an error. This is the default.
+	ErrorOnUnmatched UnmatchedMode = iota
+
+	// PassThroughOnUnmatched sends the request over the network as normal.
+	PassThroughOnUnmatched
+
+	// StubOnUnmatched returns an empty 200 OK response.
+	StubOnUnmatched
+)
+
+// recordedHeaders are the request headers that are folded into a request's
+// cache key. Keeping this list small means headers that vary between
+// otherwise-identical requests (e.g. User-Agent) don't cause cache misses.
+var recordedHeaders = []string{"Accept", "Content-Type"}
+
+// recordedExchange is a single request/response pair as written to a
+// recording file.
+type recordedExchange struct {
+	Method         string
+	URL            string
+	RequestHeader  http.Header
+	RequestBody    []byte
+	Status         int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	Elapsed        time.Duration
+}
+
+// record is the unit gob-encoded to (and decoded from) a recording file.
+type record struct {
+	Key      string
+	Exchange recordedExchange
+}
+
+// requestKey returns a canonical hash of req, used to match requests made
+// during replay against exchanges captured during recording.
+func requestKey(req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", req.Method, canonicalURL(req.URL))
+
+	for _, name := range recordedHeaders {
+		fmt.Fprintf(h, "%s: %s\n", name, req.Header.Get(name))
+	}
+
+	bodyHash := sha256.Sum256(body)
+	h.Write(bodyHash[:])
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalURL renders u with its query parameters sorted, so that two
+// requests differing only in query parameter order hash the same.
+func canonicalURL(u *url.URL) string {
+	query := u.Query()
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(u.Scheme)
+	sb.WriteString("://")
+	sb.WriteString(u.Host)
+	sb.WriteString(u.Path)
+
+	for _, k := range keys {
+		vals := append([]string(nil), query[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			fmt.Fprintf(&sb, "?%s=%s", k, v)
+		}
+	}
+
+	return sb.String()
+}
+
+// transportOrDefault returns t, or a private clone of http.DefaultTransport
+// if t is nil. It clones rather than returning http.DefaultTransport itself
+// so that wrappers which reach into it and mutate it in place (e.g.
+// Pipeline.SetProxy) only ever affect the Pipeline they were installed on,
+// never the shared global transport.
+func transportOrDefault(t http.RoundTripper) http.RoundTripper {
+	if t != nil {
+		return t
+	}
+	return http.DefaultTransport.(*http.Transport).Clone()
+}
+
+// recordingTransport wraps a RoundTripper, writing every request/response
+// pair it sees to an append-only gob-encoded file.
+type recordingTransport struct {
+	next http.RoundTripper
+	enc  *gob.Encoder
+
+	mu       sync.Mutex
+	scrubber ScrubberFunc
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	scrubbed := req.Clone(req.Context())
+	t.mu.Lock()
+	scrubber := t.scrubber
+	t.mu.Unlock()
+	if scrubber != nil {
+		scrubber(scrubbed)
+	}
+
+	key := requestKey(scrubbed, reqBody)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		return resp, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	rec := record{
+		Key: key,
+		Exchange: recordedExchange{
+			Method:         scrubbed.Method,
+			URL:            scrubbed.URL.String(),
+			RequestHeader:  scrubbed.Header,
+			RequestBody:    reqBody,
+			Status:         resp.StatusCode,
+			ResponseHeader: resp.Header,
+			ResponseBody:   respBody,
+			Elapsed:        elapsed,
+		},
+	}
+
+	t.mu.Lock()
+	err = t.enc.Encode(rec)
+	t.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("gahttp: failed to write recording: %s", err)
+	}
+
+	return resp, nil
+}
+
+// replayingTransport answers requests from a map of exchanges built from a
+// recording file, instead of hitting the network.
+type replayingTransport struct {
+	next      http.RoundTripper
+	exchanges map[string]recordedExchange
+
+	mu   sync.Mutex
+	mode UnmatchedMode
+}
+
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	ex, ok := t.exchanges[requestKey(req, body)]
+	if !ok {
+		t.mu.Lock()
+		mode := t.mode
+		t.mu.Unlock()
+
+		switch mode {
+		case PassThroughOnUnmatched:
+			return t.next.RoundTrip(req)
+		case StubOnUnmatched:
+			return &http.Response{
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+				Request:    req,
+			}, nil
+		default:
+			return nil, fmt.Errorf("gahttp: no recorded response for %s %s", req.Method, req.URL)
+		}
+	}
+
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+		StatusCode: ex.Status,
+		Header:     ex.ResponseHeader,
+		Body:       ioutil.NopCloser(bytes.NewReader(ex.ResponseBody)),
+		Request:    req,
+	}, nil
+}
+
+// defaultConcurrency is used by constructors that don't take a concurrency
+// argument of their own; callers can change it afterwards with
+// Pipeline.SetConcurrency.
+const defaultConcurrency = 20
+
+// NewRecordingPipeline returns a Pipeline whose HTTP client transparently
+// records every request/response pair it makes to filename as gob-encoded,
+// append-only records. Use Pipeline.SetScrubber to strip sensitive headers
+// before they're written.
+func NewRecordingPipeline(filename string) (*Pipeline, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("gahttp: failed to create recording file: %s", err)
+	}
+
+	p := New(defaultConcurrency)
+	p.HTTPClient.Transport = &recordingTransport{
+		next: transportOrDefault(p.HTTPClient.Transport),
+		enc:  gob.NewEncoder(f),
+	}
+
+	return p, nil
+}
+
+// NewReplayingPipeline returns a Pipeline whose HTTP client answers requests
+// from a recording previously made with NewRecordingPipeline instead of
+// hitting the network. By default, requests with no matching recorded
+// exchange fail with an error; use Pipeline.SetUnmatchedMode to change that.
+func NewReplayingPipeline(filename string) (*Pipeline, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("gahttp: failed to open recording file: %s", err)
+	}
+	defer f.Close()
+
+	exchanges := make(map[string]recordedExchange)
+
+	dec := gob.NewDecoder(f)
+	for {
+		var rec record
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("gahttp: failed to read recording: %s", err)
+		}
+		exchanges[rec.Key] = rec.Exchange
+	}
+
+	p := New(defaultConcurrency)
+	p.HTTPClient.Transport = &replayingTransport{
+		next:      transportOrDefault(p.HTTPClient.Transport),
+		exchanges: exchanges,
+	}
+
+	return p, nil
+}
+
+// SetScrubber installs fn on a recording pipeline's transport so it can
+// strip sensitive data from requests (e.g. Authorization or Cookie headers)
+// before they're written to the recording. It returns an error if p was not
+// created with NewRecordingPipeline.
+func (p *Pipeline) SetScrubber(fn ScrubberFunc) error {
+	t, ok := p.HTTPClient.Transport.(*recordingTransport)
+	if !ok {
+		return fmt.Errorf("gahttp: SetScrubber requires a pipeline created with NewRecordingPipeline")
+	}
+
+	t.mu.Lock()
+	t.scrubber = fn
+	t.mu.Unlock()
+
+	return nil
+}
+
+// SetUnmatchedMode controls what a replaying pipeline does when a request
+// has no matching recorded exchange. It returns an error if p was not
+// created with NewReplayingPipeline.
+func (p *Pipeline) SetUnmatchedMode(mode UnmatchedMode) error {
+	t, ok := p.HTTPClient.Transport.(*replayingTransport)
+	if !ok {
+		return fmt.Errorf("gahttp: SetUnmatchedMode requires a pipeline created with NewReplayingPipeline")
+	}
+
+	t.mu.Lock()
+	t.mode = mode
+	t.mu.Unlock()
+
+	return nil
+}
+
+// Unwrap returns t's underlying RoundTripper, letting callers (e.g.
+// Pipeline.SetProxy) find an installed *http.Transport through it.
+func (t *recordingTransport) Unwrap() http.RoundTripper { return t.next }
+
+// Unwrap returns t's underlying RoundTripper, letting callers (e.g.
+// Pipeline.SetProxy) find an installed *http.Transport through it.
+func (t *replayingTransport) Unwrap() http.RoundTripper { return t.next }
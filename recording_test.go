@@ -0,0 +1,94 @@
+package gahttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "1")
+		w.Write([]byte("recorded response"))
+	}))
+
+	f, err := ioutil.TempFile("", "gahttp-recording-*.gob")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %s", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	rec, err := NewRecordingPipeline(f.Name())
+	if err != nil {
+		t.Fatalf("NewRecordingPipeline: %s", err)
+	}
+
+	rec.Get(ts.URL, func(req *http.Request, resp *http.Response, err error) {
+		if err != nil {
+			t.Fatalf("recording request failed: %s", err)
+		}
+	})
+	rec.Done()
+	rec.Wait()
+
+	ts.Close()
+
+	replay, err := NewReplayingPipeline(f.Name())
+	if err != nil {
+		t.Fatalf("NewReplayingPipeline: %s", err)
+	}
+
+	replay.Get(ts.URL, func(req *http.Request, resp *http.Response, err error) {
+		if err != nil {
+			t.Fatalf("replayed request failed: %s", err)
+		}
+
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read replayed body: %s", err)
+		}
+
+		if string(b) != "recorded response" {
+			t.Errorf("want 'recorded response'; have %q", b)
+		}
+
+		if resp.Header.Get("X-Test") != "1" {
+			t.Errorf("want X-Test response header to be replayed")
+		}
+	})
+	replay.Done()
+	replay.Wait()
+}
+
+func TestReplayUnmatchedStub(t *testing.T) {
+	f, err := ioutil.TempFile("", "gahttp-recording-*.gob")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %s", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	replay, err := NewReplayingPipeline(f.Name())
+	if err != nil {
+		t.Fatalf("NewReplayingPipeline: %s", err)
+	}
+
+	if err := replay.SetUnmatchedMode(StubOnUnmatched); err != nil {
+		t.Fatalf("SetUnmatchedMode: %s", err)
+	}
+
+	replay.Get("http://example.invalid/", func(req *http.Request, resp *http.Response, err error) {
+		if err != nil {
+			t.Fatalf("want stubbed response; have error %s", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("want 200; have %d", resp.StatusCode)
+		}
+	})
+	replay.Done()
+	replay.Wait()
+}
@@ -0,0 +1,231 @@
+package gahttp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy decides whether a request should be retried after an attempt,
+// and how long to wait before making the next one.
+type RetryPolicy interface {
+	// ShouldRetry is called after each attempt, including the first. attempt
+	// is 1 on the first call. It returns whether another attempt should be
+	// made, and how long to wait before making it.
+	ShouldRetry(resp *http.Response, err error, attempt int) (bool, time.Duration)
+}
+
+// ExponentialBackoffPolicy retries up to MaxRetries times on any error or
+// response, waiting Base*2^attempt plus up to Jitter extra between
+// attempts.
+type ExponentialBackoffPolicy struct {
+	MaxRetries int
+	Base       time.Duration
+	Jitter     time.Duration
+}
+
+// ShouldRetry implements RetryPolicy
+func (p ExponentialBackoffPolicy) ShouldRetry(resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	if attempt > p.MaxRetries {
+		return false, 0
+	}
+
+	wait := p.Base * time.Duration(int64(1)<<uint(attempt-1))
+	if p.Jitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(p.Jitter)))
+	}
+
+	return true, wait
+}
+
+// Retry5xxPolicy retries up to MaxRetries times on 5xx responses or network
+// errors, waiting a fixed Wait between attempts.
+type Retry5xxPolicy struct {
+	MaxRetries int
+	Wait       time.Duration
+}
+
+// ShouldRetry implements RetryPolicy
+func (p Retry5xxPolicy) ShouldRetry(resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	if attempt > p.MaxRetries {
+		return false, 0
+	}
+
+	if err == nil && (resp == nil || resp.StatusCode < 500) {
+		return false, 0
+	}
+
+	return true, p.Wait
+}
+
+// RetryAfterPolicy behaves like Retry5xxPolicy, but waits for as long as a
+// Retry-After response header asks, falling back to its fixed Wait when the
+// header is absent or unparseable.
+type RetryAfterPolicy struct {
+	MaxRetries int
+	Wait       time.Duration
+}
+
+// ShouldRetry implements RetryPolicy
+func (p RetryAfterPolicy) ShouldRetry(resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	if attempt > p.MaxRetries {
+		return false, 0
+	}
+
+	if err == nil && (resp == nil || (resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests)) {
+		return false, 0
+	}
+
+	if resp != nil {
+		if s := resp.Header.Get("Retry-After"); s != "" {
+			if secs, err := strconv.Atoi(s); err == nil {
+				return true, time.Duration(secs) * time.Second
+			}
+		}
+	}
+
+	return true, p.Wait
+}
+
+// ensureGetBody makes sure req.GetBody is set so its body can be resent,
+// snapshotting it from req.Body if necessary.
+func ensureGetBody(req *http.Request) error {
+	if req.GetBody != nil || req.Body == nil {
+		return nil
+	}
+
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+
+	return nil
+}
+
+// Retry returns a ProcFn decorator that retries the request up to n times
+// according to policy before handing the final response to fn, resending
+// req.Body via req.GetBody on each attempt. By the time fn runs, Pipeline's
+// own dispatch has already drained req.Body once, so this only works for
+// requests whose GetBody was populated before that first dispatch -
+// exactly the byte-buffer requests (*bytes.Buffer, *bytes.Reader,
+// *strings.Reader) that http.NewRequest sets GetBody for automatically.
+// Requests without one are passed through unretried rather than resent with
+// a silently empty body. Because it runs after Pipeline's own dispatch,
+// retries are made with http.DefaultClient; use Pipeline.SetRetryPolicy
+// instead to retry within Pipeline's own client and to support arbitrary
+// request bodies.
+func Retry(n int, policy RetryPolicy) func(ProcFn) ProcFn {
+	return func(fn ProcFn) ProcFn {
+		return func(req *http.Request, resp *http.Response, err error) {
+			for attempt := 1; attempt <= n; attempt++ {
+				retry, wait := policy.ShouldRetry(resp, err, attempt)
+				if !retry || req.GetBody == nil {
+					break
+				}
+
+				time.Sleep(wait)
+
+				body, berr := req.GetBody()
+				if berr != nil {
+					err = berr
+					break
+				}
+				next := req.Clone(req.Context())
+				next.Body = body
+
+				if resp != nil && resp.Body != nil {
+					resp.Body.Close()
+				}
+
+				resp, err = http.DefaultClient.Do(next)
+			}
+
+			fn(req, resp, err)
+		}
+	}
+}
+
+// retryTransport wraps a RoundTripper, retrying requests according to
+// policy before returning a response.
+type retryTransport struct {
+	next http.RoundTripper
+
+	mu     sync.Mutex
+	policy RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := ensureGetBody(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+
+	t.mu.Lock()
+	policy := t.policy
+	t.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		retry, wait := policy.ShouldRetry(resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+
+		time.Sleep(wait)
+
+		next := req
+		if req.GetBody != nil {
+			body, berr := req.GetBody()
+			if berr != nil {
+				return resp, err
+			}
+			next = req.Clone(req.Context())
+			next.Body = body
+		}
+
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+
+		resp, err = t.next.RoundTrip(next)
+	}
+}
+
+// Unwrap returns t's underlying RoundTripper, letting callers (e.g.
+// Pipeline.SetProxy) find an installed *http.Transport through it.
+func (t *retryTransport) Unwrap() http.RoundTripper { return t.next }
+
+// retryTransportFor returns p's *retryTransport, installing one in front of
+// its current transport if one isn't already there.
+func (p *Pipeline) retryTransportFor() *retryTransport {
+	if t, ok := p.HTTPClient.Transport.(*retryTransport); ok {
+		return t
+	}
+
+	t := &retryTransport{next: transportOrDefault(p.HTTPClient.Transport)}
+	p.HTTPClient.Transport = t
+
+	return t
+}
+
+// SetRetryPolicy installs policy so that every request p makes is retried
+// according to it, without callers needing to wrap their ProcFn in Retry.
+// Calling it again replaces the policy in place rather than stacking a
+// second retry loop.
+func (p *Pipeline) SetRetryPolicy(policy RetryPolicy) {
+	t := p.retryTransportFor()
+
+	t.mu.Lock()
+	t.policy = policy
+	t.mu.Unlock()
+}
@@ -0,0 +1,66 @@
+package gahttp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoffPolicy(t *testing.T) {
+	p := ExponentialBackoffPolicy{MaxRetries: 2, Base: time.Millisecond}
+
+	retry, wait := p.ShouldRetry(nil, nil, 1)
+	if !retry || wait != time.Millisecond {
+		t.Errorf("attempt 1: want retry=true wait=1ms; have retry=%v wait=%s", retry, wait)
+	}
+
+	if retry, _ := p.ShouldRetry(nil, nil, 3); retry {
+		t.Errorf("attempt 3: want retry=false once MaxRetries is exceeded")
+	}
+}
+
+func TestRetry5xxPolicy(t *testing.T) {
+	p := Retry5xxPolicy{MaxRetries: 1, Wait: time.Millisecond}
+
+	if retry, _ := p.ShouldRetry(&http.Response{StatusCode: 500}, nil, 1); !retry {
+		t.Errorf("want retry on 500")
+	}
+
+	if retry, _ := p.ShouldRetry(&http.Response{StatusCode: 404}, nil, 1); retry {
+		t.Errorf("want no retry on 404")
+	}
+}
+
+func TestRetryAfterPolicyRetriesOn429(t *testing.T) {
+	p := RetryAfterPolicy{MaxRetries: 1, Wait: time.Second}
+
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"2"}},
+	}
+
+	retry, wait := p.ShouldRetry(resp, nil, 1)
+	if !retry {
+		t.Fatalf("want retry on 429 (the canonical Retry-After case)")
+	}
+	if wait != 2*time.Second {
+		t.Errorf("want wait to honor Retry-After header; have %s", wait)
+	}
+}
+
+func TestSetRetryPolicyReplacesInPlace(t *testing.T) {
+	p := New(1)
+
+	p.SetRetryPolicy(Retry5xxPolicy{MaxRetries: 1})
+	p.SetRetryPolicy(Retry5xxPolicy{MaxRetries: 3})
+
+	rt, ok := p.HTTPClient.Transport.(*retryTransport)
+	if !ok {
+		t.Fatalf("want a single *retryTransport installed; have %T", p.HTTPClient.Transport)
+	}
+
+	policy, ok := rt.policy.(Retry5xxPolicy)
+	if !ok || policy.MaxRetries != 3 {
+		t.Errorf("want second SetRetryPolicy call to replace the policy in place; have %+v", rt.policy)
+	}
+}